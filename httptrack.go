@@ -25,6 +25,11 @@
 // You can use all of the normal net/http functions, and just use `httptrack.AddContextData(req http.Request)` to set
 // the values for you.
 //
+// If you're calling into a third-party library that accepts a *http.Client rather than building requests
+// itself (the AWS SDK, go-kit, gRPC-gateway, etc.), use `httptrack.Client(ctx)` instead, which returns a
+// *http.Client pre-configured with `httptrack.Transport` so the tracking values are applied automatically
+// to every request it sends.
+//
 package httptrack
 
 import (
@@ -39,6 +44,10 @@ const (
 	LocationHeader     = 1
 	LocationCookie     = 2
 	LocationQueryParam = 3
+	// LocationResponseHeader, used as a Value's OutboundLocation, writes the value back onto the
+	// inbound response instead of an outbound request -- eg to echo a tracking id back to the client
+	// so a browser or load balancer can log it. It's not a valid InboundLocation.
+	LocationResponseHeader = 4
 )
 
 // When a value is found in `InboundLocation` named `InboundName`, set
@@ -61,10 +70,39 @@ type Value struct {
 	// NOTE: This function is called only once per inbound request, so you can use it to generate a
 	// new random tracking ID and all outbound calls for that request will share the same tracking ID.
 	MissingFunc func(string, http.Request) string
+	// An optional function applied to the value between the inbound read and the outbound write, eg to
+	// sign it, hash it, or reject it outright. It runs on whatever value was resolved (whether read
+	// from the inbound request or produced by MissingFunc). If it returns an error, Handler responds
+	// with http.StatusInternalServerError and does not call next.
+	Transform func(string) (string, error)
 }
 
-// There are no Options currently available to set.
+// AlwaysValue describes a value that is injected into every outbound request for an inbound request,
+// regardless of what the inbound request itself contained -- eg a fixed `x-service-name` header, or a
+// build SHA computed once at startup. Either set Value to a literal string, or Func to compute one from
+// the inbound request (eg to sign something derived from it); Func takes precedence if both are set.
+type AlwaysValue struct {
+	OutboundLocation int
+	OutboundName     string
+	Value            string
+	// An optional function, called once per inbound request, that computes the value to inject. Takes
+	// precedence over Value if set.
+	Func func(r *http.Request) string
+}
+
+// Options configures optional, cross-cutting behaviour of Handler.
 type Options struct {
+	// Always lists values that should be set on every outbound request for an inbound request, on top
+	// of whatever Handler's values parameter resolves. See AlwaysValue.
+	Always []AlwaysValue
+	// Sources registers a Source for a custom InboundLocation, so a Value can read from somewhere other
+	// than a header, cookie or query param (eg JWTClaimSource, PathParamSource, FormValueSource). The
+	// built-in locations (LocationHeader, LocationCookie, LocationQueryParam) are always available and
+	// don't need to be registered here.
+	Sources map[int]Source
+	// Sinks registers a Sink for a custom OutboundLocation, so a Value can write somewhere other than a
+	// header, cookie or query param. See Sources.
+	Sinks map[int]Sink
 }
 
 // Internal, this is how we pass through the name+value we need to set on the outbound request
@@ -72,11 +110,31 @@ type ctxValue struct {
 	location int
 	name     string
 	value    string
+	// sink is the Sink that AddContextData uses to apply this value to an outbound request. It's nil
+	// for values whose location isn't meant to be applied to outbound requests at all, eg
+	// LocationResponseHeader.
+	sink Sink
 }
 
 type ctxDataName string
 type ctxData struct {
 	values []ctxValue
+	// applied records that AddContextData has already written these values onto a request, so that
+	// running it again for the same request -- eg because the request was built via
+	// httptrack.NewRequestWithContext and then sent through httptrack.Client/Transport -- is a no-op
+	// rather than appending every header/cookie/query value a second time.
+	applied bool
+}
+
+// mergeCtxValues returns a context with vs appended to any httptrack outbound values already present
+// in ctx (eg those set by a previously-run Handler, TraceContext, or B3), so that multiple pieces of
+// httptrack middleware can each contribute outbound values independently, regardless of nesting order.
+func mergeCtxValues(ctx context.Context, vs ...ctxValue) context.Context {
+	data, _ := ctx.Value(ctxDataName("httptrack")).(ctxData)
+	merged := make([]ctxValue, 0, len(data.values)+len(vs))
+	merged = append(merged, data.values...)
+	merged = append(merged, vs...)
+	return context.WithValue(ctx, ctxDataName("httptrack"), ctxData{values: merged})
 }
 
 // Add this handler as middleware for your http server.
@@ -87,46 +145,85 @@ type ctxData struct {
 //  http.ListenAndServe("127.0.0.1:3000", handler)
 func Handler(next http.Handler, options Options, values []Value) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		h := r.Header
 		var ctxValues []ctxValue
 		for _, v := range values {
 			var outboundValue string
-			switch v.InboundLocation {
-			case LocationHeader:
-				if val := h.Get(v.InboundName); val != "" {
+			if src := sourceFor(options, v.InboundLocation); src != nil {
+				if val, ok := src.Extract(r, v.InboundName); ok {
 					outboundValue = val
 				}
-			case LocationCookie:
-				cookie, err := r.Cookie(v.InboundName)
-				if err == nil && cookie.Value != "" {
-					outboundValue = cookie.Value
-				}
-			case LocationQueryParam:
-				if r.URL != nil {
-					val := r.URL.Query().Get(v.InboundName)
-					if val != "" {
-						outboundValue = val
-					}
-				}
 			}
 			// If value is missing and there is a missing func, then call it
 			if outboundValue == "" && v.MissingFunc != nil {
 				outboundValue = v.MissingFunc(v.OutboundName, *r)
 			}
+			if outboundValue != "" && v.Transform != nil {
+				transformed, err := v.Transform(outboundValue)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				outboundValue = transformed
+			}
 			if outboundValue != "" {
 				ctxValues = append(ctxValues, ctxValue{
 					location: v.OutboundLocation,
 					name:     v.OutboundName,
 					value:    outboundValue,
+					sink:     sinkFor(options, v.OutboundLocation),
 				})
 
 			}
 		}
-		r = r.WithContext(context.WithValue(r.Context(), ctxDataName("httptrack"), ctxData{values: ctxValues}))
-		next.ServeHTTP(w, r)
+		for _, av := range options.Always {
+			value := av.Value
+			if av.Func != nil {
+				value = av.Func(r)
+			}
+			if value != "" {
+				ctxValues = append(ctxValues, ctxValue{
+					location: av.OutboundLocation,
+					name:     av.OutboundName,
+					value:    value,
+					sink:     sinkFor(options, av.OutboundLocation),
+				})
+			}
+		}
+		// Merge with any httptrack data already on r.Context() (eg from TraceContext/B3) rather than
+		// overwriting it, so Handler composes with other httptrack middleware regardless of nesting
+		// order.
+		ctx := mergeCtxValues(r.Context(), ctxValues...)
+		r = r.WithContext(ctx)
+		data, _ := ctx.Value(ctxDataName("httptrack")).(ctxData)
+		next.ServeHTTP(newResponseWriter(w, data.values), r)
 	})
 }
 
+// ResolvedValue is a single outbound location/name/value that Handler resolved from an inbound
+// request. It's what FromContext returns.
+type ResolvedValue struct {
+	Location int
+	Name     string
+	Value    string
+}
+
+// FromContext returns the outbound values Handler resolved from the inbound request that ctx belongs
+// to, together with any that TraceContext or B3 added to ctx. This is useful for code that isn't
+// building an outbound http.Request -- eg for logging, or for stamping tracking values onto non-HTTP
+// egress like a Kafka message. It returns nil if ctx has no httptrack data (eg ctx wasn't set in
+// httptrack.Handler, TraceContext or B3, or has become context.Background() at some point).
+func FromContext(ctx context.Context) []ResolvedValue {
+	data, ok := ctx.Value(ctxDataName("httptrack")).(ctxData)
+	if !ok {
+		return nil
+	}
+	out := make([]ResolvedValue, len(data.values))
+	for i, v := range data.values {
+		out[i] = ResolvedValue{Location: v.location, Name: v.name, Value: v.value}
+	}
+	return out
+}
+
 var ErrMissingContext = errors.New("no httptrack data found in context. Probably ctx wasn't set in httptrack.Handler or has become context.Background() at some point")
 
 // Create a new http.Request, but setting the appropriate outbound header/query/cookie (as specified in the original
@@ -161,51 +258,49 @@ func NewRequestWithContext(ctx context.Context, method, url string, body io.Read
 // NOTE: If you don't mind if there is no tracking data set, then you should ignore the ErrMissingContext returned
 // via:
 //  if errors.Is(err, httptrack.ErrMissingContext)
+//
+// AddContextData is idempotent per request: once it has applied req's values, it marks req's context as
+// applied, so running it again for the same req (eg because a request already built via
+// httptrack.NewRequestWithContext is then sent through httptrack.Client/Transport, which also calls
+// AddContextData) does nothing instead of appending every value a second time.
 func AddContextData(req *http.Request) error {
 	data, ok := req.Context().Value(ctxDataName("httptrack")).(ctxData)
 	if !ok {
 		return ErrMissingContext
 	}
+	if data.applied {
+		return nil
+	}
 	for _, v := range data.values {
-		switch v.location {
-		case LocationHeader:
-			req.Header.Add(v.name, v.value)
-		case LocationCookie:
-			req.AddCookie(&http.Cookie{
-				Name:  v.name,
-				Value: v.value,
-			})
-		case LocationQueryParam:
-			if req.URL == nil {
-				return errors.New("request has no URL set")
-			}
-			q := req.URL.Query()
-			q.Add(v.name, v.value)
-			req.URL.RawQuery = q.Encode()
+		// v.sink is nil for locations that don't apply to outbound requests, eg LocationResponseHeader.
+		if v.sink == nil {
+			continue
+		}
+		if err := v.sink.Apply(req, v.name, v.value); err != nil {
+			return err
 		}
 	}
+	*req = *req.WithContext(context.WithValue(req.Context(), ctxDataName("httptrack"), ctxData{values: data.values, applied: true}))
 	return nil
 }
 
-// A wrapper function around httptrack.NewRequestWithContext(ctx, "GET"...) and http.Client.Do()
+// A wrapper function around http.NewRequestWithContext(ctx, "GET"...) and httptrack.Client(ctx).Do()
 func Get(ctx context.Context, url string) (resp *http.Response, err error) {
-	req, err := NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
-	client := &http.Client{}
-	resp, err = client.Do(req)
+	resp, err = Client(ctx).Do(req)
 	return resp, err
 }
 
-// A wrapper function around httptrack.NewRequestWithContext(ctx, "POST"...) and http.Client.Do()
+// A wrapper function around http.NewRequestWithContext(ctx, "POST"...) and httptrack.Client(ctx).Do()
 func Post(ctx context.Context, url, contentType string, body io.Reader) (resp *http.Response, err error) {
-	req, err := NewRequestWithContext(ctx, "POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Add("Content-Type", contentType)
-	client := &http.Client{}
-	resp, err = client.Do(req)
+	resp, err = Client(ctx).Do(req)
 	return resp, err
 }
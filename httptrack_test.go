@@ -20,8 +20,8 @@ func ExampleHandler() {
 	// In addition, the inbound HTTP cookie named "session-id" should be converted into a HTTP
 	// header and set for all outbound calls.
 	handler := httptrack.Handler(mux, httptrack.Options{}, []httptrack.Value{
-		{httptrack.LocationHeader, "x-tracking-id", httptrack.LocationHeader, "x-tracking-id", nil},
-		{httptrack.LocationCookie, "session-id", httptrack.LocationHeader, "x-client-session-id", nil},
+		{InboundLocation: httptrack.LocationHeader, InboundName: "x-tracking-id", OutboundLocation: httptrack.LocationHeader, OutboundName: "x-tracking-id", MissingFunc: nil},
+		{InboundLocation: httptrack.LocationCookie, InboundName: "session-id", OutboundLocation: httptrack.LocationHeader, OutboundName: "x-client-session-id", MissingFunc: nil},
 	})
 
 	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
@@ -48,7 +48,7 @@ func ExampleHandler_missingfunc() {
 	// with the same name. If the inbound call does not have an x-tracking-id header, then missingFuncHandler()
 	// is called, supplying "x-tracking-id" and a copy of the inbound http.Request
 	handler := httptrack.Handler(mux, httptrack.Options{}, []httptrack.Value{
-		{httptrack.LocationHeader, "x-tracking-id", httptrack.LocationHeader, "x-tracking-id", missingFuncHandler},
+		{InboundLocation: httptrack.LocationHeader, InboundName: "x-tracking-id", OutboundLocation: httptrack.LocationHeader, OutboundName: "x-tracking-id", MissingFunc: missingFuncHandler},
 	})
 
 	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
@@ -106,9 +106,9 @@ func outboundCall(t *testing.T, ctx context.Context) {
 func TestHandler(t *testing.T) {
 	// Setup our various pass throughs.
 	trackValues := []httptrack.Value{
-		{httptrack.LocationHeader, "x-header", httptrack.LocationHeader, "x-new-header", nil},
-		{httptrack.LocationQueryParam, "query", httptrack.LocationQueryParam, "new-query", nil},
-		{httptrack.LocationCookie, "cookie", httptrack.LocationCookie, "new-cookie", nil},
+		{InboundLocation: httptrack.LocationHeader, InboundName: "x-header", OutboundLocation: httptrack.LocationHeader, OutboundName: "x-new-header", MissingFunc: nil},
+		{InboundLocation: httptrack.LocationQueryParam, InboundName: "query", OutboundLocation: httptrack.LocationQueryParam, OutboundName: "new-query", MissingFunc: nil},
+		{InboundLocation: httptrack.LocationCookie, InboundName: "cookie", OutboundLocation: httptrack.LocationCookie, OutboundName: "new-cookie", MissingFunc: nil},
 	}
 
 	// This is the external client making it's call to us. (or probably the loadbalancer making
@@ -143,7 +143,7 @@ func TestNoContext(t *testing.T) {
 
 	// Setup our various pass throughs.
 	trackValues := []httptrack.Value{
-		{httptrack.LocationHeader, "x-header", httptrack.LocationHeader, "x-new-header", nil},
+		{InboundLocation: httptrack.LocationHeader, InboundName: "x-header", OutboundLocation: httptrack.LocationHeader, OutboundName: "x-new-header", MissingFunc: nil},
 	}
 
 	// Do not put in the expected x-header
@@ -176,7 +176,7 @@ func TestMissingFunc(t *testing.T) {
 	}
 	// Look for x-header
 	vals := []httptrack.Value{
-		{httptrack.LocationHeader, "x-header", httptrack.LocationHeader, "x-header", missingFunc},
+		{InboundLocation: httptrack.LocationHeader, InboundName: "x-header", OutboundLocation: httptrack.LocationHeader, OutboundName: "x-header", MissingFunc: missingFunc},
 	}
 	// A request with no header
 	req, err := http.NewRequest("GET", "/", nil)
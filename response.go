@@ -0,0 +1,138 @@
+package httptrack
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// responseWriter wraps an http.ResponseWriter so that, just before the first call to WriteHeader or
+// Write, it sets any values resolved with OutboundLocation LocationResponseHeader (eg to echo a
+// tracking id back to the client). It does not itself implement http.Flusher, http.Hijacker or
+// http.Pusher -- see newResponseWriter, which only adds those methods to the returned value when the
+// wrapped ResponseWriter actually supports them, so capability checks like `w.(http.Hijacker)` in
+// streaming/websocket handlers keep working the way they would without httptrack in front of them.
+type responseWriter struct {
+	http.ResponseWriter
+	values     []ctxValue
+	headersSet bool
+}
+
+// newResponseWriter wraps w, returning a value that implements exactly the combination of
+// http.Flusher, http.Hijacker and http.Pusher that w itself implements -- no more, no less.
+func newResponseWriter(w http.ResponseWriter, values []ctxValue) http.ResponseWriter {
+	rw := &responseWriter{ResponseWriter: w, values: values}
+	_, isFlusher := w.(http.Flusher)
+	_, isHijacker := w.(http.Hijacker)
+	_, isPusher := w.(http.Pusher)
+	switch {
+	case isFlusher && isHijacker && isPusher:
+		return flusherHijackerPusherResponseWriter{rw}
+	case isFlusher && isHijacker:
+		return flusherHijackerResponseWriter{rw}
+	case isFlusher && isPusher:
+		return flusherPusherResponseWriter{rw}
+	case isHijacker && isPusher:
+		return hijackerPusherResponseWriter{rw}
+	case isFlusher:
+		return flusherResponseWriter{rw}
+	case isHijacker:
+		return hijackerResponseWriter{rw}
+	case isPusher:
+		return pusherResponseWriter{rw}
+	default:
+		return rw
+	}
+}
+
+func (rw *responseWriter) setHeaders() {
+	if rw.headersSet {
+		return
+	}
+	rw.headersSet = true
+	for _, v := range rw.values {
+		if v.location == LocationResponseHeader {
+			rw.Header().Set(v.name, v.value)
+		}
+	}
+}
+
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	rw.setHeaders()
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.setHeaders()
+	return rw.ResponseWriter.Write(b)
+}
+
+// flush assumes the underlying ResponseWriter implements http.Flusher -- only call it from a wrapper
+// type constructed after confirming that via newResponseWriter.
+func (rw *responseWriter) flush() {
+	rw.setHeaders()
+	rw.ResponseWriter.(http.Flusher).Flush()
+}
+
+// hijack assumes the underlying ResponseWriter implements http.Hijacker -- only call it from a wrapper
+// type constructed after confirming that via newResponseWriter.
+func (rw *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return rw.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// push assumes the underlying ResponseWriter implements http.Pusher -- only call it from a wrapper type
+// constructed after confirming that via newResponseWriter.
+func (rw *responseWriter) push(target string, opts *http.PushOptions) error {
+	return rw.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// The types below each embed *responseWriter and add exactly the optional interfaces (http.Flusher,
+// http.Hijacker, http.Pusher) that the wrapped ResponseWriter supports, so a type assertion on the
+// value newResponseWriter returns reports the same capabilities as the wrapped ResponseWriter itself.
+
+type flusherResponseWriter struct{ *responseWriter }
+
+func (w flusherResponseWriter) Flush() { w.flush() }
+
+type hijackerResponseWriter struct{ *responseWriter }
+
+func (w hijackerResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type pusherResponseWriter struct{ *responseWriter }
+
+func (w pusherResponseWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+type flusherHijackerResponseWriter struct{ *responseWriter }
+
+func (w flusherHijackerResponseWriter) Flush() { w.flush() }
+func (w flusherHijackerResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+type flusherPusherResponseWriter struct{ *responseWriter }
+
+func (w flusherPusherResponseWriter) Flush() { w.flush() }
+func (w flusherPusherResponseWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+type hijackerPusherResponseWriter struct{ *responseWriter }
+
+func (w hijackerPusherResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+func (w hijackerPusherResponseWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+type flusherHijackerPusherResponseWriter struct{ *responseWriter }
+
+func (w flusherHijackerPusherResponseWriter) Flush() { w.flush() }
+func (w flusherHijackerPusherResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+func (w flusherHijackerPusherResponseWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
@@ -0,0 +1,121 @@
+package httptrack_test
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zafnz/go-httptrack"
+)
+
+func TestResponseHeaderEcho(t *testing.T) {
+	trackValues := []httptrack.Value{
+		{
+			InboundLocation:  httptrack.LocationHeader,
+			InboundName:      "x-tracking-id",
+			OutboundLocation: httptrack.LocationResponseHeader,
+			OutboundName:     "x-tracking-id",
+		},
+	}
+
+	req, err := http.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-tracking-id", "abc123")
+
+	middleware := httptrack.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}), httptrack.Options{}, trackValues)
+
+	rr := httptest.NewRecorder()
+	middleware.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("x-tracking-id"); got != "abc123" {
+		t.Errorf("expected response header x-tracking-id=abc123, got %q", got)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	trackValues := []httptrack.Value{
+		{InboundLocation: httptrack.LocationHeader, InboundName: "x-header", OutboundLocation: httptrack.LocationHeader, OutboundName: "x-new-header"},
+	}
+
+	req, err := http.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-header", "header-value")
+
+	testCall(t, req, httptrack.Options{}, trackValues, func(w http.ResponseWriter, r *http.Request) {
+		values := httptrack.FromContext(r.Context())
+		if len(values) != 1 || values[0].Name != "x-new-header" || values[0].Value != "header-value" {
+			t.Errorf("unexpected resolved values: %+v", values)
+		}
+	})
+}
+
+// flushHijackRecorder is a minimal http.ResponseWriter that also implements http.Flusher and
+// http.Hijacker, used to verify that responseWriter forwards those to the underlying writer.
+type flushHijackRecorder struct {
+	*httptest.ResponseRecorder
+	flushed  bool
+	hijacked bool
+}
+
+func (f *flushHijackRecorder) Flush() {
+	f.flushed = true
+}
+
+func (f *flushHijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	f.hijacked = true
+	return nil, nil, nil
+}
+
+func TestResponseWriterForwardsFlusherAndHijacker(t *testing.T) {
+	req, err := http.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &flushHijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	middleware := httptrack.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+			t.Errorf("unexpected error from Hijack: %v", err)
+		}
+	}), httptrack.Options{}, nil)
+
+	middleware.ServeHTTP(rec, req)
+
+	if !rec.flushed {
+		t.Error("expected Flush to be forwarded to the underlying ResponseWriter")
+	}
+	if !rec.hijacked {
+		t.Error("expected Hijack to be forwarded to the underlying ResponseWriter")
+	}
+}
+
+// TestResponseWriterDoesNotClaimUnsupportedHijacker verifies that wrapping a ResponseWriter that
+// doesn't implement http.Hijacker (eg a plain httptest.ResponseRecorder) does not make the wrapped
+// value satisfy http.Hijacker either -- callers using the standard `hj, ok := w.(http.Hijacker)`
+// capability check must see ok == false, not a Hijack call that fails at runtime.
+func TestResponseWriterDoesNotClaimUnsupportedHijacker(t *testing.T) {
+	req, err := http.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sawHijacker bool
+	middleware := httptrack.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, sawHijacker = w.(http.Hijacker)
+	}), httptrack.Options{}, nil)
+
+	middleware.ServeHTTP(httptest.NewRecorder(), req)
+
+	if sawHijacker {
+		t.Error("expected w.(http.Hijacker) to report false when the underlying ResponseWriter doesn't support hijacking")
+	}
+}
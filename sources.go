@@ -0,0 +1,205 @@
+package httptrack
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/mux"
+)
+
+// Source extracts a named value from an inbound http.Request. HeaderSource, CookieSource and
+// QueryParamSource are the built-in Sources backing LocationHeader, LocationCookie and
+// LocationQueryParam; register your own (eg for JWT claims, path params, or form values) against a
+// custom InboundLocation via Options.Sources.
+type Source interface {
+	// Extract returns the value named name from r, and whether it was found.
+	Extract(r *http.Request, name string) (string, bool)
+}
+
+// Sink writes a named value onto an outbound http.Request. HeaderSink, CookieSink and QueryParamSink
+// are the built-in Sinks backing LocationHeader, LocationCookie and LocationQueryParam; register your
+// own against a custom OutboundLocation via Options.Sinks -- eg to attach a tracking id as gRPC
+// metadata, or as a field on a structured log line.
+type Sink interface {
+	// Apply sets the value named name on req.
+	Apply(req *http.Request, name, value string) error
+}
+
+var defaultSources = map[int]Source{
+	LocationHeader:     HeaderSource{},
+	LocationCookie:     CookieSource{},
+	LocationQueryParam: QueryParamSource{},
+}
+
+var defaultSinks = map[int]Sink{
+	LocationHeader:     HeaderSink{},
+	LocationCookie:     CookieSink{},
+	LocationQueryParam: QueryParamSink{},
+}
+
+func sourceFor(options Options, location int) Source {
+	if s, ok := options.Sources[location]; ok {
+		return s
+	}
+	return defaultSources[location]
+}
+
+func sinkFor(options Options, location int) Sink {
+	if s, ok := options.Sinks[location]; ok {
+		return s
+	}
+	return defaultSinks[location]
+}
+
+// HeaderSource extracts a value from an inbound HTTP header. It's the default Source for
+// LocationHeader.
+type HeaderSource struct{}
+
+func (HeaderSource) Extract(r *http.Request, name string) (string, bool) {
+	val := r.Header.Get(name)
+	return val, val != ""
+}
+
+// HeaderSink sets an outbound HTTP header. It's the default Sink for LocationHeader.
+type HeaderSink struct{}
+
+func (HeaderSink) Apply(req *http.Request, name, value string) error {
+	req.Header.Add(name, value)
+	return nil
+}
+
+// CookieSource extracts a value from an inbound HTTP cookie. It's the default Source for
+// LocationCookie.
+type CookieSource struct{}
+
+func (CookieSource) Extract(r *http.Request, name string) (string, bool) {
+	cookie, err := r.Cookie(name)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+// CookieSink sets an outbound HTTP cookie. It's the default Sink for LocationCookie.
+type CookieSink struct{}
+
+func (CookieSink) Apply(req *http.Request, name, value string) error {
+	req.AddCookie(&http.Cookie{Name: name, Value: value})
+	return nil
+}
+
+// QueryParamSource extracts a value from an inbound URL query parameter. It's the default Source for
+// LocationQueryParam.
+type QueryParamSource struct{}
+
+func (QueryParamSource) Extract(r *http.Request, name string) (string, bool) {
+	if r.URL == nil {
+		return "", false
+	}
+	val := r.URL.Query().Get(name)
+	return val, val != ""
+}
+
+// QueryParamSink sets an outbound URL query parameter. It's the default Sink for LocationQueryParam.
+type QueryParamSink struct{}
+
+func (QueryParamSink) Apply(req *http.Request, name, value string) error {
+	if req.URL == nil {
+		return errors.New("request has no URL set")
+	}
+	q := req.URL.Query()
+	q.Add(name, value)
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// FormValueSource extracts a value from an inbound request's form data via r.FormValue: the form body
+// (application/x-www-form-urlencoded or multipart/form-data) and the URL query parameters, with a body
+// value taking precedence over a query value of the same name. Not registered by default against any
+// location; register it against a custom InboundLocation via Options.Sources.
+type FormValueSource struct{}
+
+func (FormValueSource) Extract(r *http.Request, name string) (string, bool) {
+	val := r.FormValue(name)
+	return val, val != ""
+}
+
+// JWTClaimSource extracts a named string claim from the JWT bearer token in the inbound request's
+// Authorization header, without verifying its signature -- verifying the token is the application's
+// responsibility, eg in earlier middleware. Not registered by default against any location; register
+// it against a custom InboundLocation via Options.Sources.
+type JWTClaimSource struct{}
+
+func (JWTClaimSource) Extract(r *http.Request, name string) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok {
+		return "", false
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", false
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", false
+	}
+	val, ok := claims[name].(string)
+	if !ok || val == "" {
+		return "", false
+	}
+	return val, true
+}
+
+// PathParamSource extracts a value from a named path/route parameter. It has no default extraction
+// logic of its own -- routers disagree on how path parameters are stored on the request -- so set
+// Lookup to adapt whichever router you use. Ready-made adapters are provided for the two most common
+// ones: see ChiPathParamSource and GorillaPathParamSource. If you're on Go 1.22+ and using
+// http.ServeMux's own path parameters instead of a third-party router, there's no adapter for
+// req.PathValue here (this module's go.mod floor predates it) -- write your own one-line Lookup:
+//
+//  httptrack.PathParamSource{Lookup: func(r *http.Request, name string) (string, bool) {
+//      val := r.PathValue(name)
+//      return val, val != ""
+//  }}
+//
+// Not registered by default against any location; register it against a custom InboundLocation via
+// Options.Sources.
+type PathParamSource struct {
+	// Lookup adapts a particular router's path-parameter extraction. If nil, Extract always reports
+	// not-found.
+	Lookup func(r *http.Request, name string) (string, bool)
+}
+
+func (s PathParamSource) Extract(r *http.Request, name string) (string, bool) {
+	if s.Lookup == nil {
+		return "", false
+	}
+	return s.Lookup(r, name)
+}
+
+// ChiPathParamSource returns a PathParamSource that reads path parameters populated by
+// github.com/go-chi/chi/v5's router.
+func ChiPathParamSource() PathParamSource {
+	return PathParamSource{Lookup: func(r *http.Request, name string) (string, bool) {
+		val := chi.URLParam(r, name)
+		return val, val != ""
+	}}
+}
+
+// GorillaPathParamSource returns a PathParamSource that reads path parameters populated by
+// github.com/gorilla/mux's router.
+func GorillaPathParamSource() PathParamSource {
+	return PathParamSource{Lookup: func(r *http.Request, name string) (string, bool) {
+		val, ok := mux.Vars(r)[name]
+		return val, ok
+	}}
+}
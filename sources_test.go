@@ -0,0 +1,185 @@
+package httptrack_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/mux"
+	"github.com/zafnz/go-httptrack"
+)
+
+func TestCustomSource(t *testing.T) {
+	const LocationConstant = 100
+	options := httptrack.Options{
+		Sources: map[int]httptrack.Source{
+			LocationConstant: constSource{"const-value"},
+		},
+	}
+	trackValues := []httptrack.Value{
+		{InboundLocation: LocationConstant, InboundName: "whatever", OutboundLocation: httptrack.LocationHeader, OutboundName: "x-new-header"},
+	}
+
+	req, err := http.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCall(t, req, options, trackValues, func(w http.ResponseWriter, r *http.Request) {
+		newReq, err := httptrack.NewRequestWithContext(r.Context(), "GET", "/downstream", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := newReq.Header.Get("x-new-header"); got != "const-value" {
+			t.Errorf("expected x-new-header=const-value, got %q", got)
+		}
+	})
+}
+
+type constSource struct{ value string }
+
+func (s constSource) Extract(r *http.Request, name string) (string, bool) {
+	return s.value, true
+}
+
+func TestCustomSink(t *testing.T) {
+	const LocationConstant = 101
+	var gotName, gotValue string
+	options := httptrack.Options{
+		Sinks: map[int]httptrack.Sink{
+			LocationConstant: sinkFunc(func(req *http.Request, name, value string) error {
+				gotName, gotValue = name, value
+				return nil
+			}),
+		},
+	}
+	trackValues := []httptrack.Value{
+		{InboundLocation: httptrack.LocationHeader, InboundName: "x-header", OutboundLocation: LocationConstant, OutboundName: "custom-sink-name"},
+	}
+
+	req, err := http.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-header", "header-value")
+
+	testCall(t, req, options, trackValues, func(w http.ResponseWriter, r *http.Request) {
+		if _, err := httptrack.NewRequestWithContext(r.Context(), "GET", "/downstream", nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if gotName != "custom-sink-name" || gotValue != "header-value" {
+		t.Errorf("expected custom sink to be called with (custom-sink-name, header-value), got (%q, %q)", gotName, gotValue)
+	}
+}
+
+type sinkFunc func(req *http.Request, name, value string) error
+
+func (f sinkFunc) Apply(req *http.Request, name, value string) error {
+	return f(req, name, value)
+}
+
+func TestJWTClaimSource(t *testing.T) {
+	payload, err := json.Marshal(map[string]string{"sub": "user-123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := "header." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+
+	src := httptrack.JWTClaimSource{}
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	val, ok := src.Extract(req, "sub")
+	if !ok || val != "user-123" {
+		t.Errorf("expected sub=user-123, got %q (found=%v)", val, ok)
+	}
+
+	if _, ok := src.Extract(req, "missing"); ok {
+		t.Error("expected missing claim to not be found")
+	}
+}
+
+func TestPathParamSourceLookup(t *testing.T) {
+	src := httptrack.PathParamSource{Lookup: func(r *http.Request, name string) (string, bool) {
+		if name == "id" {
+			return "42", true
+		}
+		return "", false
+	}}
+	req, err := http.NewRequest("GET", "/widgets/42", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, ok := src.Extract(req, "id")
+	if !ok || val != "42" {
+		t.Errorf("expected id=42, got %q (found=%v)", val, ok)
+	}
+}
+
+func TestPathParamSourceNoLookup(t *testing.T) {
+	src := httptrack.PathParamSource{}
+	req, err := http.NewRequest("GET", "/widgets/42", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := src.Extract(req, "id"); ok {
+		t.Error("expected a zero-value PathParamSource to never find a value")
+	}
+}
+
+func TestChiPathParamSource(t *testing.T) {
+	src := httptrack.ChiPathParamSource()
+	var gotVal string
+	var gotOk bool
+	router := chi.NewRouter()
+	router.Get("/widgets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		gotVal, gotOk = src.Extract(r, "id")
+	})
+
+	req := httptest.NewRequest("GET", "/widgets/42", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !gotOk || gotVal != "42" {
+		t.Errorf("expected id=42, got %q (found=%v)", gotVal, gotOk)
+	}
+}
+
+func TestGorillaPathParamSource(t *testing.T) {
+	src := httptrack.GorillaPathParamSource()
+	var gotVal string
+	var gotOk bool
+	router := mux.NewRouter()
+	router.HandleFunc("/widgets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		gotVal, gotOk = src.Extract(r, "id")
+	})
+
+	req := httptest.NewRequest("GET", "/widgets/42", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !gotOk || gotVal != "42" {
+		t.Errorf("expected id=42, got %q (found=%v)", gotVal, gotOk)
+	}
+}
+
+func TestFormValueSource(t *testing.T) {
+	src := httptrack.FormValueSource{}
+	req, err := http.NewRequest("GET", "/?name=widget", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, ok := src.Extract(req, "name")
+	if !ok || val != "widget" {
+		t.Errorf("expected name=widget, got %q (found=%v)", val, ok)
+	}
+}
@@ -0,0 +1,153 @@
+package httptrack
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type traceIDKeyType struct{}
+type spanIDKeyType struct{}
+
+var traceIDKey traceIDKeyType
+var spanIDKey spanIDKeyType
+
+// TraceContext returns middleware implementing W3C Trace Context propagation
+// (https://www.w3.org/TR/trace-context/) via the "traceparent" header. Install it in front of (or
+// instead of) httptrack.Handler:
+//
+//  handler := httptrack.TraceContext()(httptrack.Handler(mux, httptrack.Options{}, values))
+//
+// If the inbound request has a well-formed traceparent header, its trace-id and flags (including the
+// sampled bit) are propagated unchanged to every outbound call made for this request; otherwise a new
+// trace-id is minted and flagged as sampled. Either way, a fresh span-id is generated (using
+// crypto/rand) for this hop. The resolved ids are stored in the request's context -- see
+// TraceIDFromContext and SpanIDFromContext -- and a traceparent header carrying them is automatically
+// applied to outbound requests made via httptrack.NewRequestWithContext, httptrack.AddContextData, or
+// httptrack.Transport.
+func TraceContext() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			traceID, flags, ok := parseTraceparent(r.Header.Get("traceparent"))
+			if !ok {
+				id, err := newHexID(16)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				traceID = id
+				flags = "01" // sampled, since there's no inbound decision to honour
+			}
+			spanID, err := newHexID(8)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			traceparent := fmt.Sprintf("00-%s-%s-%s", traceID, spanID, flags)
+
+			ctx := context.WithValue(r.Context(), traceIDKey, traceID)
+			ctx = context.WithValue(ctx, spanIDKey, spanID)
+			ctx = mergeCtxValues(ctx, ctxValue{location: LocationHeader, name: "traceparent", value: traceparent, sink: HeaderSink{}})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// B3 returns middleware implementing Zipkin's B3 multi-header propagation
+// (https://github.com/openzipkin/b3-propagation) via the x-b3-traceid, x-b3-spanid and x-b3-sampled
+// headers. It behaves like TraceContext, but using B3's headers instead of W3C Trace Context's
+// traceparent: the trace-id and sampled decision are propagated unchanged if present and valid, a
+// fresh span-id is minted for this hop, and the resulting ids are available via
+// TraceIDFromContext/SpanIDFromContext and applied automatically to outbound requests.
+func B3() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			traceID := r.Header.Get("x-b3-traceid")
+			if !isValidB3TraceID(traceID) {
+				id, err := newHexID(16)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				traceID = id
+			}
+			sampled := r.Header.Get("x-b3-sampled")
+			if sampled != "0" && sampled != "1" {
+				sampled = "1" // sampled, since there's no valid inbound decision to honour
+			}
+			spanID, err := newHexID(8)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), traceIDKey, traceID)
+			ctx = context.WithValue(ctx, spanIDKey, spanID)
+			ctx = mergeCtxValues(ctx,
+				ctxValue{location: LocationHeader, name: "x-b3-traceid", value: traceID, sink: HeaderSink{}},
+				ctxValue{location: LocationHeader, name: "x-b3-spanid", value: spanID, sink: HeaderSink{}},
+				ctxValue{location: LocationHeader, name: "x-b3-sampled", value: sampled, sink: HeaderSink{}},
+			)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TraceIDFromContext returns the trace-id resolved by TraceContext or B3 for ctx, and whether one was
+// found.
+func TraceIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(traceIDKey).(string)
+	return id, ok
+}
+
+// SpanIDFromContext returns the span-id resolved by TraceContext or B3 for ctx, and whether one was
+// found.
+func SpanIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(spanIDKey).(string)
+	return id, ok
+}
+
+// parseTraceparent validates and extracts the trace-id and flags from a W3C traceparent header value
+// of the form "version-traceid-parentid-flags" (eg "00-<32hex>-<16hex>-<2hex>"). It returns false if h
+// isn't well-formed.
+func parseTraceparent(h string) (traceID, flags string, ok bool) {
+	if len(h) != 55 {
+		return "", "", false
+	}
+	version, traceID, parentID, flags := h[0:2], h[3:35], h[36:52], h[53:55]
+	if h[2] != '-' || h[35] != '-' || h[52] != '-' {
+		return "", "", false
+	}
+	if !isHex(version) || !isHex(traceID) || !isHex(parentID) || !isHex(flags) {
+		return "", "", false
+	}
+	return traceID, flags, true
+}
+
+// isValidB3TraceID reports whether s is a valid B3 trace-id: 16 or 32 lowercase hex characters.
+func isValidB3TraceID(s string) bool {
+	if len(s) != 16 && len(s) != 32 {
+		return false
+	}
+	if strings.ToLower(s) != s {
+		return false
+	}
+	return isHex(s)
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// newHexID returns a random n-byte id, lowercase hex encoded.
+func newHexID(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
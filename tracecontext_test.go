@@ -0,0 +1,171 @@
+package httptrack_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zafnz/go-httptrack"
+)
+
+func TestTraceContextMintsNewIDs(t *testing.T) {
+	var gotTraceparent string
+	handler := httptrack.TraceContext()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID, ok := httptrack.TraceIDFromContext(r.Context())
+		if !ok || traceID == "" {
+			t.Error("TraceIDFromContext did not return a trace-id")
+		}
+		if _, ok := httptrack.SpanIDFromContext(r.Context()); !ok {
+			t.Error("SpanIDFromContext did not return a span-id")
+		}
+		req, err := httptrack.NewRequestWithContext(r.Context(), "GET", "/downstream", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotTraceparent = req.Header.Get("traceparent")
+	}))
+
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	parts := strings.Split(gotTraceparent, "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		t.Errorf("unexpected traceparent: %q", gotTraceparent)
+	}
+}
+
+func TestTraceContextPropagatesTraceID(t *testing.T) {
+	const inbound = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	var gotTraceparent string
+	handler := httptrack.TraceContext()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := httptrack.NewRequestWithContext(r.Context(), "GET", "/downstream", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotTraceparent = req.Header.Get("traceparent")
+	}))
+
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("traceparent", inbound)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	parts := strings.Split(gotTraceparent, "-")
+	if len(parts) != 4 || parts[1] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected trace-id to be propagated unchanged, got traceparent %q", gotTraceparent)
+	}
+	if parts[2] == "00f067aa0ba902b7" {
+		t.Error("expected a new span-id to be generated, got the inbound parent-id unchanged")
+	}
+}
+
+func TestTraceContextPropagatesNonSampled(t *testing.T) {
+	const inbound = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+	var gotTraceparent string
+	handler := httptrack.TraceContext()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := httptrack.NewRequestWithContext(r.Context(), "GET", "/downstream", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotTraceparent = req.Header.Get("traceparent")
+	}))
+
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("traceparent", inbound)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	parts := strings.Split(gotTraceparent, "-")
+	if len(parts) != 4 || parts[3] != "00" {
+		t.Errorf("expected the non-sampled flag to be propagated unchanged, got traceparent %q", gotTraceparent)
+	}
+}
+
+// TestTraceContextComposesWithHandler verifies the nesting order documented by TraceContext --
+// TraceContext()(Handler(...)) -- actually propagates the traceparent header to outbound calls, ie
+// that Handler merges with (rather than overwrites) the httptrack data TraceContext already put on
+// the request's context.
+func TestTraceContextComposesWithHandler(t *testing.T) {
+	var gotTraceparent, gotTrackingID string
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := httptrack.NewRequestWithContext(r.Context(), "GET", "/downstream", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotTraceparent = req.Header.Get("traceparent")
+		gotTrackingID = req.Header.Get("x-tracking-id")
+	})
+
+	trackValues := []httptrack.Value{
+		{InboundLocation: httptrack.LocationHeader, InboundName: "x-tracking-id", OutboundLocation: httptrack.LocationHeader, OutboundName: "x-tracking-id"},
+	}
+	handler := httptrack.TraceContext()(httptrack.Handler(inner, httptrack.Options{}, trackValues))
+
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-tracking-id", "abc123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotTraceparent == "" {
+		t.Error("expected traceparent to reach the outbound request when nested as TraceContext()(Handler(...))")
+	}
+	if gotTrackingID != "abc123" {
+		t.Errorf("expected x-tracking-id=abc123, got %q", gotTrackingID)
+	}
+}
+
+func TestB3MintsNewIDs(t *testing.T) {
+	var gotTraceID, gotSpanID, gotSampled string
+	handler := httptrack.B3()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := httptrack.NewRequestWithContext(r.Context(), "GET", "/downstream", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotTraceID = req.Header.Get("x-b3-traceid")
+		gotSpanID = req.Header.Get("x-b3-spanid")
+		gotSampled = req.Header.Get("x-b3-sampled")
+	}))
+
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(gotTraceID) != 32 || len(gotSpanID) != 16 || gotSampled != "1" {
+		t.Errorf("unexpected B3 headers: traceid=%q spanid=%q sampled=%q", gotTraceID, gotSpanID, gotSampled)
+	}
+}
+
+func TestB3PropagatesNonSampled(t *testing.T) {
+	var gotSampled string
+	handler := httptrack.B3()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := httptrack.NewRequestWithContext(r.Context(), "GET", "/downstream", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotSampled = req.Header.Get("x-b3-sampled")
+	}))
+
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-b3-traceid", "4bf92f3577b34da6a3ce929d0e0e4736")
+	req.Header.Set("x-b3-sampled", "0")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotSampled != "0" {
+		t.Errorf("expected the non-sampled decision to be propagated unchanged, got x-b3-sampled=%q", gotSampled)
+	}
+}
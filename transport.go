@@ -0,0 +1,60 @@
+package httptrack
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Transport is an http.RoundTripper that automatically applies the tracking values carried in a
+// request's context (as set by httptrack.Handler) before sending it. Wrap it around any
+// http.RoundTripper to get the same behaviour as AddContextData, but without having to remember to
+// call it yourself -- this is what makes httptrack work with third-party clients (go-kit, the AWS SDK,
+// gRPC-gateway, etc.) that accept an http.RoundTripper or *http.Client rather than building requests
+// via httptrack.NewRequestWithContext.
+//
+// The zero value is valid and uses http.DefaultTransport as its Base.
+type Transport struct {
+	// Base is the underlying http.RoundTripper used to actually send the request. If nil,
+	// http.DefaultTransport is used.
+	Base http.RoundTripper
+	// fallbackCtx is used when req itself carries no tracking data, eg because it was built by
+	// http.Client's convenience methods (Get, Post, ...), which always use context.Background(). Set
+	// via Client.
+	fallbackCtx context.Context
+}
+
+// RoundTrip implements http.RoundTripper. It clones req, applies the tracking values found in
+// req.Context() (see AddContextData), and then delegates to Base. If req's own context has no
+// httptrack data, but Transport has a fallbackCtx (see Client), that context's tracking values are
+// applied instead. If neither has httptrack data, the request is sent unmodified.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	req = req.Clone(req.Context())
+	err := AddContextData(req)
+	if err != nil && !errors.Is(err, ErrMissingContext) {
+		return nil, err
+	}
+	if errors.Is(err, ErrMissingContext) && t.fallbackCtx != nil {
+		fallbackReq := req.Clone(t.fallbackCtx)
+		if err := AddContextData(fallbackReq); err != nil && !errors.Is(err, ErrMissingContext) {
+			return nil, err
+		}
+		req = fallbackReq
+	}
+	return base.RoundTrip(req)
+}
+
+// Client returns an *http.Client whose Transport applies the tracking values from ctx to every
+// request it sends -- including requests built by its convenience methods (Get, Post, Head, ...),
+// which otherwise carry no context of their own. Use it to plug httptrack into code that accepts an
+// *http.Client rather than building requests itself:
+//
+//  client := httptrack.Client(ctx)
+//  resp, err := client.Get("http://microservice1.example.com/serviceCall")
+func Client(ctx context.Context) *http.Client {
+	return &http.Client{Transport: &Transport{fallbackCtx: ctx}}
+}
@@ -0,0 +1,134 @@
+package httptrack_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zafnz/go-httptrack"
+)
+
+func TestTransport(t *testing.T) {
+	var gotHeader string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("x-new-header")
+	}))
+	defer downstream.Close()
+
+	trackValues := []httptrack.Value{
+		{InboundLocation: httptrack.LocationHeader, InboundName: "x-header", OutboundLocation: httptrack.LocationHeader, OutboundName: "x-new-header", MissingFunc: nil},
+	}
+
+	req, err := http.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-header", "header-value")
+
+	testCall(t, req, httptrack.Options{}, trackValues, func(w http.ResponseWriter, r *http.Request) {
+		// Build the outbound request directly with http.NewRequestWithContext -- ie without ever
+		// calling httptrack.NewRequestWithContext or AddContextData -- and rely entirely on
+		// httptrack.Client to apply the tracking header.
+		outReq, err := http.NewRequestWithContext(r.Context(), "GET", downstream.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := httptrack.Client(r.Context()).Do(outReq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	})
+
+	if gotHeader != "header-value" {
+		t.Errorf("expected downstream to receive x-new-header=header-value, got %q", gotHeader)
+	}
+}
+
+// TestTransportDoesNotReapplyAlreadyAppliedRequest verifies that a request which already had its
+// tracking values applied -- eg one built via httptrack.NewRequestWithContext -- is not re-applied a
+// second time when sent through httptrack.Client/Transport, which also calls AddContextData.
+func TestTransportDoesNotReapplyAlreadyAppliedRequest(t *testing.T) {
+	var gotHeaders []string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeaders = r.Header["X-New-Header"]
+	}))
+	defer downstream.Close()
+
+	trackValues := []httptrack.Value{
+		{InboundLocation: httptrack.LocationHeader, InboundName: "x-header", OutboundLocation: httptrack.LocationHeader, OutboundName: "x-new-header"},
+	}
+
+	req, err := http.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-header", "header-value")
+
+	testCall(t, req, httptrack.Options{}, trackValues, func(w http.ResponseWriter, r *http.Request) {
+		outReq, err := httptrack.NewRequestWithContext(r.Context(), "GET", downstream.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := httptrack.Client(r.Context()).Do(outReq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	})
+
+	if len(gotHeaders) != 1 || gotHeaders[0] != "header-value" {
+		t.Errorf("expected exactly one x-new-header=header-value, got %v", gotHeaders)
+	}
+}
+
+func TestTransportNoContext(t *testing.T) {
+	var gotHeader string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("x-new-header")
+	}))
+	defer downstream.Close()
+
+	resp, err := httptrack.Client(context.Background()).Get(downstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if gotHeader != "" {
+		t.Errorf("expected no x-new-header to be set, got %q", gotHeader)
+	}
+}
+
+// TestClientConvenienceMethodsUseBoundContext verifies the doc example on Client/Transport: that
+// client.Get(url) -- which builds its request with context.Background(), not the ctx passed to
+// Client -- still picks up the tracking values resolved for ctx.
+func TestClientConvenienceMethodsUseBoundContext(t *testing.T) {
+	var gotHeader string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("x-new-header")
+	}))
+	defer downstream.Close()
+
+	trackValues := []httptrack.Value{
+		{InboundLocation: httptrack.LocationHeader, InboundName: "x-header", OutboundLocation: httptrack.LocationHeader, OutboundName: "x-new-header"},
+	}
+
+	req, err := http.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-header", "header-value")
+
+	testCall(t, req, httptrack.Options{}, trackValues, func(w http.ResponseWriter, r *http.Request) {
+		resp, err := httptrack.Client(r.Context()).Get(downstream.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	})
+
+	if gotHeader != "header-value" {
+		t.Errorf("expected downstream to receive x-new-header=header-value, got %q", gotHeader)
+	}
+}
@@ -0,0 +1,105 @@
+package httptrack_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zafnz/go-httptrack"
+)
+
+func TestValueTransform(t *testing.T) {
+	trackValues := []httptrack.Value{
+		{
+			InboundLocation:  httptrack.LocationHeader,
+			InboundName:      "x-header",
+			OutboundLocation: httptrack.LocationHeader,
+			OutboundName:     "x-new-header",
+			Transform: func(s string) (string, error) {
+				return strings.ToUpper(s), nil
+			},
+		},
+	}
+
+	req, err := http.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-header", "header-value")
+
+	testCall(t, req, httptrack.Options{}, trackValues, func(w http.ResponseWriter, r *http.Request) {
+		newReq, err := httptrack.NewRequestWithContext(r.Context(), "GET", "/downstream", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := newReq.Header.Get("x-new-header"); got != "HEADER-VALUE" {
+			t.Errorf("expected transformed value HEADER-VALUE, got %q", got)
+		}
+	})
+}
+
+func TestValueTransformError(t *testing.T) {
+	trackValues := []httptrack.Value{
+		{
+			InboundLocation:  httptrack.LocationHeader,
+			InboundName:      "x-header",
+			OutboundLocation: httptrack.LocationHeader,
+			OutboundName:     "x-new-header",
+			Transform: func(s string) (string, error) {
+				return "", errors.New("transform failed")
+			},
+		},
+	}
+
+	req, err := http.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-header", "header-value")
+
+	called := false
+	middleware := httptrack.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}), httptrack.Options{}, trackValues)
+
+	rr := httptest.NewRecorder()
+	middleware.ServeHTTP(rr, req)
+
+	if called {
+		t.Error("expected next handler not to be called when Transform errors")
+	}
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 status, got %d", rr.Code)
+	}
+}
+
+func TestOptionsAlways(t *testing.T) {
+	options := httptrack.Options{
+		Always: []httptrack.AlwaysValue{
+			{OutboundLocation: httptrack.LocationHeader, OutboundName: "x-service-name", Value: "my-service"},
+			{OutboundLocation: httptrack.LocationHeader, OutboundName: "x-computed", Func: func(r *http.Request) string {
+				return "computed-" + r.URL.Path
+			}},
+		},
+	}
+
+	req, err := http.NewRequest("GET", "/widgets", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCall(t, req, options, nil, func(w http.ResponseWriter, r *http.Request) {
+		newReq, err := httptrack.NewRequestWithContext(r.Context(), "GET", "/downstream", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := newReq.Header.Get("x-service-name"); got != "my-service" {
+			t.Errorf("expected x-service-name=my-service, got %q", got)
+		}
+		if got := newReq.Header.Get("x-computed"); got != "computed-/widgets" {
+			t.Errorf("expected x-computed=computed-/widgets, got %q", got)
+		}
+	})
+}